@@ -0,0 +1,37 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// Command-line flags for the controller.
+
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// Args holds all of the controller's command-line flags, as pointers returned directly
+// by the flag package.
+type Args struct {
+	debug     *bool
+	interval  *time.Duration
+	pprofAddr *string
+	cgroup    *bool
+}
+
+// gArgs holds the parsed command-line flags. It's populated once by ParseArgs at startup.
+var gArgs Args
+
+// ParseArgs defines and parses the controller's command-line flags into gArgs. It must be
+// called once, before main starts any of the stats sources that read from gArgs.
+func ParseArgs() {
+	gArgs = Args{
+		debug:    flag.Bool("debug", false, "enable verbose debug logging"),
+		interval: flag.Duration("interval", time.Second, "sampling interval for all stats sources"),
+		pprofAddr: flag.String("pprof-addr", "",
+			"if set, expose net/http/pprof debug endpoints on this host:port (e.g. localhost:6060)"),
+		cgroup: flag.Bool("cgroup", false,
+			"report this container's cgroup resource usage instead of the host's (Linux only)"),
+	}
+	flag.Parse()
+}
@@ -0,0 +1,320 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// +build linux
+
+// Read cgroup v1/v2 accounting files for --cgroup mode, so the OLED reflects the
+// container's resource usage rather than the host's when running inside Docker.
+
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StartSystemSource starts CgroupStats if --cgroup was given, or SystemStatsDetailed
+// otherwise, so the caller can dispatch to whichever source is relevant without caring
+// which platform it's running on (see sysmode_other.go for the non-Linux fallback).
+func StartSystemSource(interval time.Duration, results chan SystemSample, quit chan bool) {
+	if *gArgs.cgroup {
+		go CgroupStats(interval, results, quit)
+		return
+	}
+	go SystemStatsDetailed(interval, results, quit)
+}
+
+type cgroupVersion int
+
+const (
+	cgroupUnknown cgroupVersion = iota
+	cgroupV1
+	cgroupV2
+)
+
+// cgroupRoot is where cgroupfs is conventionally mounted. The root itself is not
+// autodetected; detectCgroupVersion only detects which hierarchy version is mounted here.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// detectCgroupVersion autodetects the cgroup hierarchy version. v2 mounts a single
+// unified hierarchy with a cgroup.controllers file at its root; v1 splits controllers
+// into separate per-subsystem directories such as cpu/, memory/, and blkio/.
+func detectCgroupVersion() cgroupVersion {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return cgroupV2
+	}
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "memory")); err == nil {
+		return cgroupV1
+	}
+	return cgroupUnknown
+}
+
+// CgroupStats samples the current process's cgroup CPU, memory, disk, and network usage
+// at the specified interval and feeds the results to the specified channel, in the same
+// SystemSample shape as SystemStatsDetailed so a single widget can render either one.
+func CgroupStats(interval time.Duration, results chan SystemSample, quit chan bool) {
+	defer close(results)
+
+	version := detectCgroupVersion()
+	if version == cgroupUnknown {
+		log.Println("No cgroup accounting files found under", cgroupRoot, "- disabling cgroup stats")
+		return
+	}
+
+	results <- SystemSample{}
+
+	cpuLimit := readCgroupCPULimit(version)
+
+	var prevCPUNanos uint64
+	var prevDiskRead, prevDiskWrite uint64
+	var prevNet map[string]netCounters
+	prevTime := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(prevTime).Seconds()
+			prevTime = now
+
+			var sample SystemSample
+
+			if cpuNanos, err := readCgroupCPUNanos(version); err != nil {
+				log.Println("Failed to read cgroup CPU usage:", err)
+			} else {
+				if elapsed > 0 && prevCPUNanos != 0 {
+					deltaSeconds := float64(cpuNanos-prevCPUNanos) / 1e9
+					sample.CPUTotal = deltaSeconds / elapsed / cpuLimit
+				}
+				prevCPUNanos = cpuNanos
+			}
+
+			if used, limit, err := readCgroupMemory(version); err != nil {
+				log.Println("Failed to read cgroup memory usage:", err)
+			} else {
+				sample.MemUsed = used
+				if limit > 0 {
+					sample.MemPercent = float64(used) / float64(limit)
+				}
+			}
+
+			if read, write, err := readCgroupBlkio(version); err != nil {
+				log.Println("Failed to read cgroup blkio counters:", err)
+			} else {
+				if elapsed > 0 && (prevDiskRead != 0 || prevDiskWrite != 0) {
+					sample.DiskReadBps = deltaPerSec(prevDiskRead, read, elapsed)
+					sample.DiskWriteBps = deltaPerSec(prevDiskWrite, write, elapsed)
+				}
+				prevDiskRead, prevDiskWrite = read, write
+			}
+
+			// The container shares its network namespace with this process, so
+			// /proc/self/net/dev is already scoped to the container rather than the host.
+			if current, err := readNetCountersFromPath("/proc/self/net/dev"); err != nil {
+				log.Println("Failed to read cgroup network counters:", err)
+			} else {
+				sample.NetRxBps = make(map[string]uint64, len(current))
+				sample.NetTxBps = make(map[string]uint64, len(current))
+				for name, cur := range current {
+					if prev, ok := prevNet[name]; ok && elapsed > 0 {
+						sample.NetRxBps[name] = deltaPerSec(prev.bytesRecv, cur.bytesRecv, elapsed)
+						sample.NetTxBps[name] = deltaPerSec(prev.bytesSent, cur.bytesSent, elapsed)
+					}
+				}
+				prevNet = current
+			}
+
+			results <- sample
+		}
+	}
+}
+
+// cgroupSelfPath returns this process's cgroup path for the given v1 controller (e.g.
+// "cpuacct", "memory", "blkio"), as found in /proc/self/cgroup. Pass "" for the v2
+// unified hierarchy, whose line has no controller name.
+func cgroupSelfPath(controller string) (string, error) {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if controller == "" && fields[1] == "" {
+			return fields[2], nil
+		}
+		for _, c := range strings.Split(fields[1], ",") {
+			if c == controller {
+				return fields[2], nil
+			}
+		}
+	}
+
+	return "", os.ErrNotExist
+}
+
+// readCgroupCPULimit returns the number of CPUs the container is allowed to use, so that
+// CPUTotal reflects the container's own quota rather than the host's full core count.
+// Falls back to the host's core count when the container has no CPU limit set.
+func readCgroupCPULimit(version cgroupVersion) float64 {
+	if version == cgroupV2 {
+		path, err := cgroupSelfPath("")
+		if err != nil {
+			return float64(runtime.NumCPU())
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(cgroupRoot, path, "cpu.max"))
+		if err != nil {
+			return float64(runtime.NumCPU())
+		}
+
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return float64(runtime.NumCPU())
+		}
+
+		quota, errQuota := strconv.ParseFloat(fields[0], 64)
+		period, errPeriod := strconv.ParseFloat(fields[1], 64)
+		if errQuota != nil || errPeriod != nil || period == 0 {
+			return float64(runtime.NumCPU())
+		}
+		return quota / period
+	}
+
+	path, err := cgroupSelfPath("cpu")
+	if err != nil {
+		return float64(runtime.NumCPU())
+	}
+
+	quota, err := readSysfsUint(filepath.Join(cgroupRoot, "cpu", path, "cpu.cfs_quota_us"))
+	if err != nil || int64(quota) <= 0 {
+		return float64(runtime.NumCPU())
+	}
+	period, err := readSysfsUint(filepath.Join(cgroupRoot, "cpu", path, "cpu.cfs_period_us"))
+	if err != nil || period == 0 {
+		return float64(runtime.NumCPU())
+	}
+
+	return float64(quota) / float64(period)
+}
+
+func readCgroupCPUNanos(version cgroupVersion) (uint64, error) {
+	if version == cgroupV2 {
+		path, err := cgroupSelfPath("")
+		if err != nil {
+			return 0, err
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(cgroupRoot, path, "cpu.stat"))
+		if err != nil {
+			return 0, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "usage_usec" {
+				usec, _ := strconv.ParseUint(fields[1], 10, 64)
+				return usec * 1000, nil
+			}
+		}
+		return 0, os.ErrInvalid
+	}
+
+	path, err := cgroupSelfPath("cpuacct")
+	if err != nil {
+		return 0, err
+	}
+	return readSysfsUint(filepath.Join(cgroupRoot, "cpuacct", path, "cpuacct.usage"))
+}
+
+func readCgroupMemory(version cgroupVersion) (used, limit uint64, err error) {
+	if version == cgroupV2 {
+		path, err := cgroupSelfPath("")
+		if err != nil {
+			return 0, 0, err
+		}
+
+		used, err = readSysfsUint(filepath.Join(cgroupRoot, path, "memory.current"))
+		if err != nil {
+			return 0, 0, err
+		}
+
+		limitRaw, err := ioutil.ReadFile(filepath.Join(cgroupRoot, path, "memory.max"))
+		if err != nil {
+			return used, 0, nil
+		}
+		if s := strings.TrimSpace(string(limitRaw)); s != "max" {
+			limit, _ = strconv.ParseUint(s, 10, 64)
+		}
+		return used, limit, nil
+	}
+
+	path, err := cgroupSelfPath("memory")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	used, err = readSysfsUint(filepath.Join(cgroupRoot, "memory", path, "memory.usage_in_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, _ = readSysfsUint(filepath.Join(cgroupRoot, "memory", path, "memory.limit_in_bytes"))
+	return used, limit, nil
+}
+
+// readCgroupBlkio sums read and write bytes across all backing block devices reported in
+// blkio.throttle.io_service_bytes (v1). There is no widely-available v2 equivalent
+// (io.stat is only populated for cgroups with the io controller enabled), so on v2 this
+// simply reports zero rather than guessing.
+func readCgroupBlkio(version cgroupVersion) (read, write uint64, err error) {
+	if version == cgroupV2 {
+		return 0, 0, nil
+	}
+
+	path, err := cgroupSelfPath("blkio")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(cgroupRoot, "blkio", path, "blkio.throttle.io_service_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		value, _ := strconv.ParseUint(fields[2], 10, 64)
+		switch fields[1] {
+		case "Read":
+			read += value
+		case "Write":
+			write += value
+		}
+	}
+
+	return read, write, nil
+}
+
+func readSysfsUint(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
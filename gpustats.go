@@ -0,0 +1,205 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// Get GPU utilization, VRAM usage, and temperature, for the GPU load widget.
+
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GPUSample is a single GPU's utilization and temperature snapshot.
+type GPUSample struct {
+	Name        string
+	UtilPercent float64
+	VRAMPercent float64
+	TempC       float64
+}
+
+type gpuBackend int
+
+const (
+	gpuBackendNone gpuBackend = iota
+	gpuBackendNvidia
+	gpuBackendAMD
+)
+
+// detectGPUBackend probes for nvidia-smi and AMD's sysfs interface, in that order, and
+// picks whichever is found first. It's cheap enough to call once at startup rather than
+// on every sample.
+func detectGPUBackend() gpuBackend {
+	if _, err := exec.LookPath("nvidia-smi"); err == nil {
+		return gpuBackendNvidia
+	}
+	if matches, err := filepath.Glob("/sys/class/drm/card*/device/gpu_busy_percent"); err == nil && len(matches) > 0 {
+		return gpuBackendAMD
+	}
+	return gpuBackendNone
+}
+
+// GPUStats reports GPU utilization, VRAM usage, and temperature at the specified interval.
+// It detects the available GPU backend once at startup and closes the results channel
+// immediately, without sampling, if no supported GPU is present.
+func GPUStats(interval time.Duration, results chan []GPUSample, quit chan bool) {
+	defer close(results)
+
+	backend := detectGPUBackend()
+	if backend == gpuBackendNone {
+		log.Println("No supported GPU found, disabling GPU stats")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			var samples []GPUSample
+			var err error
+
+			switch backend {
+			case gpuBackendNvidia:
+				samples, err = nvidiaGPUSamples()
+			case gpuBackendAMD:
+				samples, err = amdGPUSamples()
+			}
+
+			if err != nil {
+				log.Println("Failed to read GPU stats:", err)
+				continue
+			}
+
+			results <- samples
+		}
+	}
+}
+
+// nvidiaGPUSamples shells out to nvidia-smi and parses its CSV output. One line is
+// reported per GPU, in nvidia-smi's own enumeration order.
+func nvidiaGPUSamples() ([]GPUSample, error) {
+	cmd := exec.Command("nvidia-smi",
+		"--query-gpu=name,utilization.gpu,memory.used,memory.total,temperature.gpu",
+		"--format=csv,noheader,nounits")
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []GPUSample
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) != 5 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		util, _ := strconv.ParseFloat(fields[1], 64)
+		used, _ := strconv.ParseFloat(fields[2], 64)
+		total, _ := strconv.ParseFloat(fields[3], 64)
+		temp, _ := strconv.ParseFloat(fields[4], 64)
+
+		var vramPercent float64
+		if total > 0 {
+			vramPercent = used / total * 100
+		}
+
+		samples = append(samples, GPUSample{
+			Name:        fields[0],
+			UtilPercent: util,
+			VRAMPercent: vramPercent,
+			TempC:       temp,
+		})
+	}
+
+	return samples, scanner.Err()
+}
+
+// amdGPUSamples reads AMD's amdgpu sysfs interface directly: gpu_busy_percent for
+// utilization, mem_info_vram_{used,total} for VRAM, and the card's hwmon temp1_input for
+// temperature.
+func amdGPUSamples() ([]GPUSample, error) {
+	cards, err := filepath.Glob("/sys/class/drm/card*/device/gpu_busy_percent")
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]GPUSample, 0, len(cards))
+	for _, busyFile := range cards {
+		dir := filepath.Dir(busyFile)
+
+		busy, err := readSysfsFloat(busyFile)
+		if err != nil {
+			continue
+		}
+
+		var vramPercent float64
+		if used, errUsed := readSysfsFloat(filepath.Join(dir, "mem_info_vram_used")); errUsed == nil {
+			if total, errTotal := readSysfsFloat(filepath.Join(dir, "mem_info_vram_total")); errTotal == nil && total > 0 {
+				vramPercent = used / total * 100
+			}
+		}
+
+		temp, _ := readHwmonTempC(dir)
+
+		samples = append(samples, GPUSample{
+			Name:        filepath.Base(filepath.Dir(dir)),
+			UtilPercent: busy,
+			VRAMPercent: vramPercent,
+			TempC:       temp,
+		})
+	}
+
+	return samples, nil
+}
+
+func readSysfsFloat(path string) (float64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
+
+// readHwmonTempC finds the GPU's hwmon temp1_input file (reported in millidegrees) under
+// the card's device directory and returns the temperature in degrees Celsius.
+func readHwmonTempC(deviceDir string) (float64, error) {
+	matches, err := filepath.Glob(filepath.Join(deviceDir, "hwmon", "hwmon*", "temp1_input"))
+	if err != nil {
+		return 0, err
+	}
+	if len(matches) == 0 {
+		return 0, os.ErrNotExist
+	}
+
+	milliC, err := readSysfsFloat(matches[0])
+	if err != nil {
+		return 0, err
+	}
+	return milliC / 1000, nil
+}
+
+// RenderGPUWidget draws GPU load next to CPU load onto the OLED. There is no display
+// driver in this tree yet, so for now this just logs what the widget would show.
+func RenderGPUWidget(samples []GPUSample) {
+	for _, sample := range samples {
+		log.Printf("[gpu] %s: Util %.1f%%  VRAM %.1f%%  Temp %.1f°C\n",
+			sample.Name, sample.UtilPercent, sample.VRAMPercent, sample.TempC)
+	}
+}
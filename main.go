@@ -0,0 +1,83 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// Entrypoint: parses flags, starts the enabled stats sources, and renders their samples.
+//
+// There is no OLED display driver in this tree yet, so each RenderXWidget function below
+// is a stub that logs what it would have drawn. That keeps the wiring between a stats
+// source and its widget real and testable ahead of the display driver landing, rather than
+// leaving the stats sources unreferenced.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+func main() {
+	ParseArgs()
+
+	StartPprof()
+
+	quit := make(chan bool)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		close(quit)
+	}()
+
+	runWidgets(quit)
+}
+
+// runWidgets starts every enabled stats source and feeds its samples to the matching
+// widget renderer, each on its own goroutine, until quit is closed.
+func runWidgets(quit chan bool) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		system := make(chan SystemSample, 5)
+		StartSystemSource(*gArgs.interval, system, quit)
+		for sample := range system {
+			RenderSystemWidget(sample)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		procs := make(chan []ProcessSample, 5)
+		go ProcessStats(*gArgs.interval, procs, quit, ProcessStatsOptions{})
+		for samples := range procs {
+			RenderProcessWidget(samples)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		net := make(chan []InterfaceSample, 5)
+		go NetworkStats(*gArgs.interval, net, quit)
+		for samples := range net {
+			RenderNetworkWidget(samples)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		gpu := make(chan []GPUSample, 5)
+		go GPUStats(*gArgs.interval, gpu, quit)
+		for samples := range gpu {
+			RenderGPUWidget(samples)
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// Get per-interface network throughput, for the upload/download sparkline widget.
+
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// InterfaceSample is a single network interface's throughput, sampled over the preceding
+// interval, and its error and drop counters, which are cumulative totals rather than
+// per-interval counts.
+type InterfaceSample struct {
+	Name string
+
+	RxBytesPerSec   uint64
+	TxBytesPerSec   uint64
+	RxPacketsPerSec uint64
+	TxPacketsPerSec uint64
+
+	RxErrors uint64
+	TxErrors uint64
+	RxDrops  uint64
+	TxDrops  uint64
+}
+
+// netCounters holds the cumulative, monotonically increasing counters for one interface,
+// before they're differenced into the per-second rates reported in InterfaceSample.
+type netCounters struct {
+	bytesRecv, bytesSent     uint64
+	packetsRecv, packetsSent uint64
+	errin, errout            uint64
+	dropin, dropout          uint64
+}
+
+// NetworkStats samples per-interface network throughput at the specified interval and
+// feeds the results to the specified channel. readNetCounters is implemented per-OS in
+// netstats_linux.go (reading /proc/net/dev directly, to avoid the gopsutil dependency
+// where possible) and netstats_other.go (via gopsutil).
+func NetworkStats(interval time.Duration, results chan []InterfaceSample, quit chan bool) {
+	defer close(results)
+
+	var prev map[string]netCounters
+	prevTime := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(prevTime).Seconds()
+			prevTime = now
+
+			current, err := readNetCounters()
+			if err != nil {
+				log.Println("Failed to read network counters:", err)
+				continue
+			}
+
+			samples := make([]InterfaceSample, 0, len(current))
+			for name, cur := range current {
+				sample := InterfaceSample{
+					Name:     name,
+					RxErrors: cur.errin,
+					TxErrors: cur.errout,
+					RxDrops:  cur.dropin,
+					TxDrops:  cur.dropout,
+				}
+				if p, ok := prev[name]; ok && elapsed > 0 {
+					sample.RxBytesPerSec = deltaPerSec(p.bytesRecv, cur.bytesRecv, elapsed)
+					sample.TxBytesPerSec = deltaPerSec(p.bytesSent, cur.bytesSent, elapsed)
+					sample.RxPacketsPerSec = deltaPerSec(p.packetsRecv, cur.packetsRecv, elapsed)
+					sample.TxPacketsPerSec = deltaPerSec(p.packetsSent, cur.packetsSent, elapsed)
+				}
+				samples = append(samples, sample)
+			}
+			prev = current
+
+			results <- samples
+		}
+	}
+}
+
+// primaryInterface picks the busiest non-loopback interface from a sample set, for widgets
+// that only have room to show a single interface's throughput.
+func primaryInterface(samples []InterfaceSample) string {
+	var best InterfaceSample
+	var bestTotal uint64
+
+	for _, sample := range samples {
+		if strings.HasPrefix(sample.Name, "lo") {
+			continue
+		}
+		if total := sample.RxBytesPerSec + sample.TxBytesPerSec; total >= bestTotal {
+			best, bestTotal = sample, total
+		}
+	}
+
+	return best.Name
+}
+
+// NetSparkline keeps a rolling window of recent throughput samples for a single interface,
+// feeding the OLED's compact upload/download sparkline widget.
+type NetSparkline struct {
+	rx, tx []uint64
+	size   int
+}
+
+// NewNetSparkline creates a sparkline window holding up to size samples.
+func NewNetSparkline(size int) *NetSparkline {
+	return &NetSparkline{size: size}
+}
+
+// Push records the latest sample, trimming the window down to size.
+func (s *NetSparkline) Push(sample InterfaceSample) {
+	s.rx = append(s.rx, sample.RxBytesPerSec)
+	s.tx = append(s.tx, sample.TxBytesPerSec)
+	if len(s.rx) > s.size {
+		s.rx = s.rx[len(s.rx)-s.size:]
+		s.tx = s.tx[len(s.tx)-s.size:]
+	}
+}
+
+// RxTx returns the current receive and transmit sparkline windows, oldest sample first.
+func (s *NetSparkline) RxTx() ([]uint64, []uint64) {
+	return s.rx, s.tx
+}
+
+// netSparklineWidth is the number of samples kept for the OLED's sparkline widget.
+const netSparklineWidth = 32
+
+// networkSparkline is the rolling window RenderNetworkWidget feeds, tracking whichever
+// interface was busiest the last time it changed.
+var networkSparkline = NewNetSparkline(netSparklineWidth)
+
+// RenderNetworkWidget draws a compact upload/download sparkline for the primary interface
+// onto the OLED. There is no display driver in this tree yet, so for now this just logs
+// what the widget would show.
+func RenderNetworkWidget(samples []InterfaceSample) {
+	name := primaryInterface(samples)
+	if name == "" {
+		return
+	}
+
+	for _, sample := range samples {
+		if sample.Name == name {
+			networkSparkline.Push(sample)
+			log.Printf("[net] %s: Rx %d B/s  Tx %d B/s\n", name, sample.RxBytesPerSec, sample.TxBytesPerSec)
+			return
+		}
+	}
+}
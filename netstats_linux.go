@@ -0,0 +1,71 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// +build linux
+
+// Read per-interface network counters directly from /proc/net/dev, avoiding a gopsutil
+// dependency on the platform where it would otherwise just re-read the same file.
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func readNetCounters() (map[string]netCounters, error) {
+	return readNetCountersFromPath("/proc/net/dev")
+}
+
+// readNetCountersFromPath parses a /proc/net/dev-formatted file at an arbitrary path, so
+// that CgroupStats can read a single process's namespace-scoped view (/proc/self/net/dev)
+// with the same parsing logic.
+func readNetCountersFromPath(path string) (map[string]netCounters, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counters := make(map[string]netCounters)
+
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		if i < 2 {
+			continue // Skip the two header lines.
+		}
+
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(parts[0])
+		fields := strings.Fields(parts[1])
+		if len(fields) < 16 {
+			continue
+		}
+
+		// Receive: bytes packets errs drop fifo frame compressed multicast
+		// Transmit: bytes packets errs drop fifo colls carrier compressed
+		counters[name] = netCounters{
+			bytesRecv:   parseUint(fields[0]),
+			packetsRecv: parseUint(fields[1]),
+			errin:       parseUint(fields[2]),
+			dropin:      parseUint(fields[3]),
+			bytesSent:   parseUint(fields[8]),
+			packetsSent: parseUint(fields[9]),
+			errout:      parseUint(fields[10]),
+			dropout:     parseUint(fields[11]),
+		}
+	}
+
+	return counters, scanner.Err()
+}
+
+func parseUint(s string) uint64 {
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return v
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// +build !linux
+
+// Read per-interface network counters via gopsutil, since Windows and macOS have no
+// /proc/net/dev equivalent. This replaces the old TypePerf-based approach on Windows.
+
+package main
+
+import (
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+func readNetCounters() (map[string]netCounters, error) {
+	stats, err := psnet.IOCounters(true)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string]netCounters, len(stats))
+	for _, s := range stats {
+		counters[s.Name] = netCounters{
+			bytesRecv:   s.BytesRecv,
+			bytesSent:   s.BytesSent,
+			packetsRecv: s.PacketsRecv,
+			packetsSent: s.PacketsSent,
+			errin:       s.Errin,
+			errout:      s.Errout,
+			dropin:      s.Dropin,
+			dropout:     s.Dropout,
+		}
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// Optional net/http/pprof endpoint, for diagnosing goroutine leaks and memory growth in
+// the field without attaching a debugger to a headless OLED box.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"runtime/debug"
+)
+
+// StartPprof starts the pprof HTTP server on the address given via --pprof-addr, if one
+// was set. It's a no-op otherwise. Exposes the usual /debug/pprof/{goroutine,heap,
+// threadcreate,block} profiles, plus a GC summary at /debug/gc, for the lifetime of the
+// process.
+func StartPprof() {
+	addr := *gArgs.pprofAddr
+	if addr == "" {
+		return
+	}
+
+	http.HandleFunc("/debug/gc", func(w http.ResponseWriter, r *http.Request) {
+		var stats debug.GCStats
+		debug.ReadGCStats(&stats)
+		fmt.Fprintf(w, "NumGC: %d\nLastGC: %s\nPauseTotal: %s\n", stats.NumGC, stats.LastGC, stats.PauseTotal)
+	})
+
+	go func() {
+		log.Println("Starting pprof endpoint on", addr)
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Println("pprof server failed:", err)
+		}
+	}()
+}
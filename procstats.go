@@ -0,0 +1,95 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// Get per-process CPU and memory usage, for the top-N process widget.
+
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// ProcessSortBy selects the metric used to rank processes in ProcessStats.
+type ProcessSortBy int
+
+const (
+	// SortByCPU ranks processes by CPU usage, highest first.
+	SortByCPU ProcessSortBy = iota
+	// SortByMemory ranks processes by resident memory, highest first.
+	SortByMemory
+)
+
+// ProcessStatsOptions controls how ProcessStats samples and ranks processes.
+type ProcessStatsOptions struct {
+	TopN   int           // Number of processes to report per sample. Defaults to 5.
+	SortBy ProcessSortBy // Metric to rank by.
+}
+
+// ProcessSample is a single process's resource usage at the time it was sampled.
+type ProcessSample struct {
+	PID      int
+	Name     string
+	CPU      float64 // Percent of total CPU capacity across all cores, 0-100
+	RSSBytes uint64
+}
+
+// Stat is the previous sample kept per PID so CPU% can be computed as a delta over time.
+type Stat struct {
+	utime  uint64
+	stime  uint64
+	uptime float64
+}
+
+// ProcessStats samples the process table at the specified interval and feeds a ranked,
+// top-N list of processes to the results channel, similar to pidusage's SysInfo model.
+// Dead PIDs are culled from the internal history on every cycle. Sampling itself is
+// implemented per-OS in procstats_linux.go and procstats_other.go.
+func ProcessStats(interval time.Duration, results chan []ProcessSample, quit chan bool, opts ProcessStatsOptions) {
+	defer close(results)
+
+	if opts.TopN <= 0 {
+		opts.TopN = 5
+	}
+
+	history := make(map[int]Stat)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			samples, err := sampleProcesses(history)
+			if err != nil {
+				log.Println("Failed to sample processes:", err)
+				continue
+			}
+
+			sort.Slice(samples, func(i, j int) bool {
+				if opts.SortBy == SortByMemory {
+					return samples[i].RSSBytes > samples[j].RSSBytes
+				}
+				return samples[i].CPU > samples[j].CPU
+			})
+
+			if len(samples) > opts.TopN {
+				samples = samples[:opts.TopN]
+			}
+
+			results <- samples
+		}
+	}
+}
+
+// RenderProcessWidget draws the top-N process list onto the OLED. There is no display
+// driver in this tree yet, so for now this just logs what the widget would show.
+func RenderProcessWidget(samples []ProcessSample) {
+	for i, sample := range samples {
+		log.Printf("[procs] #%d %s (pid %d): CPU %.1f%%  RSS %d bytes\n",
+			i+1, sample.Name, sample.PID, sample.CPU, sample.RSSBytes)
+	}
+}
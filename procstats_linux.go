@@ -0,0 +1,131 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// +build linux
+
+// Sample per-process CPU and memory usage directly from /proc, avoiding a gopsutil
+// dependency on the platform where it would otherwise just re-read these same files.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// clockTicksPerSec is the kernel's USER_HZ (_SC_CLK_TCK), which is 100 on effectively
+// every Linux distribution actually in use.
+const clockTicksPerSec = 100
+
+func sampleProcesses(history map[int]Stat) ([]ProcessSample, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	uptime, err := readUptime()
+	if err != nil {
+		return nil, err
+	}
+
+	numCPU := runtime.NumCPU()
+
+	seen := make(map[int]bool, len(entries))
+	samples := make([]ProcessSample, 0, len(entries))
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		stat, err := readProcStat(pid)
+		if err != nil {
+			// The process likely exited between the readdir and the read.
+			continue
+		}
+
+		seen[pid] = true
+
+		sample := ProcessSample{PID: pid, Name: stat.name, RSSBytes: stat.rss}
+
+		if prev, ok := history[pid]; ok {
+			deltaTicks := float64((stat.utime - prev.utime) + (stat.stime - prev.stime))
+			deltaUptime := uptime - prev.uptime
+			if deltaUptime > 0 {
+				sample.CPU = deltaTicks / clockTicksPerSec / deltaUptime * 100 / float64(numCPU)
+			}
+		}
+
+		history[pid] = Stat{utime: stat.utime, stime: stat.stime, uptime: uptime}
+		samples = append(samples, sample)
+	}
+
+	// Cull dead PIDs from the history.
+	for pid := range history {
+		if !seen[pid] {
+			delete(history, pid)
+		}
+	}
+
+	return samples, nil
+}
+
+type procStat struct {
+	name  string
+	utime uint64
+	stime uint64
+	rss   uint64 // in bytes
+}
+
+// readProcStat parses /proc/<pid>/stat. The comm field (index 2) is parenthesized and may
+// itself contain spaces or closing parens, so it's located from the outside in rather than
+// split on whitespace like the rest of the fields.
+func readProcStat(pid int) (procStat, error) {
+	data, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return procStat{}, err
+	}
+	line := string(data)
+
+	open := strings.IndexByte(line, '(')
+	end := strings.LastIndexByte(line, ')')
+	if open < 0 || end < 0 || end < open {
+		return procStat{}, os.ErrInvalid
+	}
+
+	name := line[open+1 : end]
+	fields := strings.Fields(line[end+2:])
+	if len(fields) < 22 {
+		return procStat{}, os.ErrInvalid
+	}
+
+	// Fields here are indexed from state (field 3 in `man proc`), so utime is fields[11],
+	// stime is fields[12], and rss (in pages) is fields[21].
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	rssPages, _ := strconv.ParseUint(fields[21], 10, 64)
+
+	return procStat{
+		name:  name,
+		utime: utime,
+		stime: stime,
+		rss:   rssPages * uint64(os.Getpagesize()),
+	}, nil
+}
+
+// readUptime returns the system uptime in seconds, from /proc/uptime.
+func readUptime() (float64, error) {
+	data, err := ioutil.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, os.ErrInvalid
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// +build !linux
+
+// Sample per-process CPU and memory usage via gopsutil's process package, since Windows
+// and macOS have no equivalent to /proc.
+
+package main
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// microsPerSec converts a float64 seconds value into integer microseconds, for storing
+// cumulative CPU time in a Stat's uint64 utime field.
+const microsPerSec = 1e6
+
+// nanosPerSec converts an integer nanosecond value, such as time.Time.UnixNano(), into
+// float64 seconds. Kept distinct from microsPerSec so the two conversions can't be
+// conflated again.
+const nanosPerSec = 1e9
+
+func sampleProcesses(history map[int]Stat) ([]ProcessSample, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	// p.CPUPercent() reports a process's lifetime average CPU usage since it started,
+	// which smooths toward that average the longer a process runs rather than reflecting
+	// its current load. Instead, delta the cumulative CPU time reported by p.Times() the
+	// same way procstats_linux.go deltas utime+stime, using a shared "now" for all
+	// processes in this cycle in place of /proc/uptime.
+	now := float64(time.Now().UnixNano()) / nanosPerSec
+	numCPU := runtime.NumCPU()
+
+	seen := make(map[int]bool, len(procs))
+	samples := make([]ProcessSample, 0, len(procs))
+
+	for _, p := range procs {
+		pid := int(p.Pid)
+
+		times, err := p.Times()
+		if err != nil {
+			// The process likely exited since the process list was fetched.
+			continue
+		}
+		name, _ := p.Name()
+
+		var rss uint64
+		if memInfo, err := p.MemoryInfo(); err == nil && memInfo != nil {
+			rss = memInfo.RSS
+		}
+
+		busyMicros := uint64((times.User + times.System) * microsPerSec)
+
+		sample := ProcessSample{PID: pid, Name: name, RSSBytes: rss}
+
+		if prev, ok := history[pid]; ok && busyMicros >= prev.utime {
+			deltaBusy := float64(busyMicros-prev.utime) / microsPerSec
+			deltaTime := now - prev.uptime
+			if deltaTime > 0 {
+				sample.CPU = deltaBusy / deltaTime * 100 / float64(numCPU)
+			}
+		}
+
+		seen[pid] = true
+		history[pid] = Stat{utime: busyMicros, uptime: now}
+		samples = append(samples, sample)
+	}
+
+	// Cull dead PIDs from the history.
+	for pid := range history {
+		if !seen[pid] {
+			delete(history, pid)
+		}
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// +build !linux
+
+// --cgroup is Linux-only; everywhere else StartSystemSource just reports host stats.
+
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// StartSystemSource starts SystemStatsDetailed. --cgroup has no effect on this platform,
+// since there is no cgroupfs to read.
+func StartSystemSource(interval time.Duration, results chan SystemSample, quit chan bool) {
+	if *gArgs.cgroup {
+		log.Println("--cgroup is only supported on Linux; reporting host stats instead")
+	}
+	go SystemStatsDetailed(interval, results, quit)
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 Albert "Drauthius" Diserholt. All rights reserved.
+// Licensed under the MIT License.
+
+// Get system status from gopsutil (cross-platform edition)
+
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// SystemSample is a richer, struct-based snapshot of the host's resource usage than the
+// legacy []float64 result, so that widgets can opt in to whichever fields they render.
+type SystemSample struct {
+	CPUTotal   float64   // Overall CPU usage, 0.0-1.0
+	CPUPerCore []float64 // Per-core CPU usage, 0.0-1.0
+
+	MemUsed      uint64  // Memory used, in bytes
+	MemAvailable uint64  // Memory available, in bytes
+	MemPercent   float64 // Memory used, 0.0-1.0
+
+	SwapUsed    uint64  // Swap used, in bytes
+	SwapTotal   uint64  // Swap total, in bytes
+	SwapPercent float64 // Swap used, 0.0-1.0
+
+	DiskPercent  float64 // Usage of the root filesystem, 0.0-1.0
+	DiskReadBps  uint64  // Aggregate disk read bytes/sec across all disks
+	DiskWriteBps uint64  // Aggregate disk write bytes/sec across all disks
+
+	NetRxBps map[string]uint64 // Per-interface receive bytes/sec
+	NetTxBps map[string]uint64 // Per-interface transmit bytes/sec
+
+	Load1  float64 // 1-minute load average
+	Load5  float64 // 5-minute load average
+	Load15 float64 // 15-minute load average
+
+	Uptime time.Duration
+}
+
+// Get system statistics at the specified interval.
+// This will get the current CPU, memory, swap (page file), and disk usage in fractions (0.0-1.0)
+//
+// Kept for backward compatibility with callers that only need the four aggregate
+// percentages. New code should use SystemStatsDetailed, which also exposes per-core
+// CPU, disk/network throughput, load average, and uptime.
+func SystemStats(interval time.Duration, results chan []float64, quit chan bool) {
+	defer close(results)
+
+	detailed := make(chan SystemSample, 5)
+	go SystemStatsDetailed(interval, detailed, quit)
+
+	for sample := range detailed {
+		results <- []float64{sample.CPUTotal, sample.MemPercent, sample.SwapPercent, sample.DiskPercent}
+	}
+}
+
+// SystemStatsDetailed samples CPU, memory, swap, disk IO, network IO, load average, and
+// uptime at the specified interval using gopsutil, and feeds the results to the specified
+// channel. Unlike the TypePerf-based implementation this replaces, it runs unmodified on
+// Windows, Linux, and macOS without spawning a subprocess, so there is no process-hang
+// failure mode to recover from.
+func SystemStatsDetailed(interval time.Duration, results chan SystemSample, quit chan bool) {
+	defer close(results)
+
+	// System status will take a second to fill up. To avoid it feeling like lag, send an empty result directly.
+	results <- SystemSample{}
+
+	var prevDiskRead, prevDiskWrite uint64
+	var prevNet map[string]psnet.IOCountersStat
+	prevTime := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(prevTime).Seconds()
+			prevTime = now
+
+			var sample SystemSample
+
+			if percents, err := cpu.Percent(0, false); err != nil {
+				log.Println("Failed to read CPU usage:", err)
+			} else if len(percents) > 0 {
+				sample.CPUTotal = percents[0] / 100
+			}
+			if perCore, err := cpu.Percent(0, true); err != nil {
+				log.Println("Failed to read per-core CPU usage:", err)
+			} else {
+				sample.CPUPerCore = make([]float64, len(perCore))
+				for i, p := range perCore {
+					sample.CPUPerCore[i] = p / 100
+				}
+			}
+
+			if vm, err := mem.VirtualMemory(); err != nil {
+				log.Println("Failed to read memory usage:", err)
+			} else {
+				sample.MemUsed = vm.Used
+				sample.MemAvailable = vm.Available
+				sample.MemPercent = vm.UsedPercent / 100
+			}
+
+			if sw, err := mem.SwapMemory(); err != nil {
+				log.Println("Failed to read swap usage:", err)
+			} else {
+				sample.SwapUsed = sw.Used
+				sample.SwapTotal = sw.Total
+				sample.SwapPercent = sw.UsedPercent / 100
+			}
+
+			if du, err := disk.Usage("/"); err != nil {
+				log.Println("Failed to read disk usage:", err)
+			} else {
+				sample.DiskPercent = du.UsedPercent / 100
+			}
+
+			if counters, err := disk.IOCounters(); err != nil {
+				log.Println("Failed to read disk IO counters:", err)
+			} else {
+				var read, write uint64
+				for _, c := range counters {
+					read += c.ReadBytes
+					write += c.WriteBytes
+				}
+				if elapsed > 0 && (prevDiskRead != 0 || prevDiskWrite != 0) {
+					sample.DiskReadBps = deltaPerSec(prevDiskRead, read, elapsed)
+					sample.DiskWriteBps = deltaPerSec(prevDiskWrite, write, elapsed)
+				}
+				prevDiskRead, prevDiskWrite = read, write
+			}
+
+			if counters, err := psnet.IOCounters(true); err != nil {
+				log.Println("Failed to read network IO counters:", err)
+			} else {
+				sample.NetRxBps = make(map[string]uint64, len(counters))
+				sample.NetTxBps = make(map[string]uint64, len(counters))
+				current := make(map[string]psnet.IOCountersStat, len(counters))
+				for _, c := range counters {
+					current[c.Name] = c
+					if prev, ok := prevNet[c.Name]; ok && elapsed > 0 {
+						sample.NetRxBps[c.Name] = deltaPerSec(prev.BytesRecv, c.BytesRecv, elapsed)
+						sample.NetTxBps[c.Name] = deltaPerSec(prev.BytesSent, c.BytesSent, elapsed)
+					}
+				}
+				prevNet = current
+			}
+
+			if avg, err := load.Avg(); err != nil {
+				log.Println("Failed to read load average:", err)
+			} else {
+				sample.Load1, sample.Load5, sample.Load15 = avg.Load1, avg.Load5, avg.Load15
+			}
+
+			if uptime, err := host.Uptime(); err != nil {
+				log.Println("Failed to read uptime:", err)
+			} else {
+				sample.Uptime = time.Duration(uptime) * time.Second
+			}
+
+			if *gArgs.debug {
+				log.Printf("CPU: %.1f%%  Mem: %.1f%%  Swap: %.1f%%  Disk: %.1f%%\n",
+					sample.CPUTotal*100, sample.MemPercent*100, sample.SwapPercent*100, sample.DiskPercent*100)
+			}
+
+			results <- sample
+		}
+	}
+}
+
+// RenderSystemWidget draws a SystemSample onto the OLED. There is no display driver in
+// this tree yet, so for now this just logs what the widget would show.
+func RenderSystemWidget(sample SystemSample) {
+	log.Printf("[system] CPU: %.1f%%  Mem: %.1f%%  Swap: %.1f%%  Disk: %.1f%%\n",
+		sample.CPUTotal*100, sample.MemPercent*100, sample.SwapPercent*100, sample.DiskPercent*100)
+}
+
+// deltaPerSec returns the rate of change between two monotonically increasing counter
+// samples, guarding against the counter having wrapped or been reset.
+func deltaPerSec(prev, cur uint64, elapsedSeconds float64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return uint64(float64(cur-prev) / elapsedSeconds)
+}